@@ -0,0 +1,61 @@
+package home
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/AdguardTeam/golibs/log"
+)
+
+// filterRefreshReqJSON is the request body for the targeted filter refresh
+// handler.
+type filterRefreshReqJSON struct {
+	// ID is the identifier of the filter list to refresh.
+	ID int64 `json:"id"`
+
+	// Whitelist, if true, looks the filter up among the allowlists instead
+	// of the blocklists.
+	Whitelist bool `json:"whitelist"`
+}
+
+// filterRefreshRespJSON is the response body for the targeted filter
+// refresh handler.
+type filterRefreshRespJSON struct {
+	// Updated is true if the filter's contents have changed as a result of
+	// the refresh.
+	Updated bool `json:"updated"`
+}
+
+// handleFilteringRefreshFilter refreshes a single filter list by ID,
+// without waiting for or blocking any other filter list refresh.
+func (f *Filtering) handleFilteringRefreshFilter(w http.ResponseWriter, r *http.Request) {
+	req := filterRefreshReqJSON{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("decoding request: %s", err), http.StatusBadRequest)
+
+		return
+	}
+
+	updated, err := f.refreshFilterByID(req.ID, req.Whitelist)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("refreshing filter: %s", err), http.StatusBadRequest)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(filterRefreshRespJSON{Updated: updated})
+	if err != nil {
+		log.Error("filtering: writing refresh_filter response: %s", err)
+	}
+}
+
+// registerFilterRefreshHandler registers the targeted, single-filter refresh
+// endpoint.  It's called alongside the existing RegisterFilteringHandlers
+// rather than folded into it, since that method is defined elsewhere and
+// redeclaring it here would either fail to compile or silently drop its
+// other routes.
+func (f *Filtering) registerFilterRefreshHandler() {
+	httpRegister(http.MethodPost, "/control/filtering/refresh_filter", f.handleFilteringRefreshFilter)
+}
@@ -1,11 +1,13 @@
 package home
 
 import (
+	"archive/zip"
 	"bufio"
+	"bytes"
+	"compress/gzip"
 	"fmt"
 	"hash/crc32"
 	"io"
-	"net/http"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -19,6 +21,16 @@ import (
 	"github.com/AdguardTeam/golibs/errors"
 	"github.com/AdguardTeam/golibs/log"
 	"github.com/AdguardTeam/golibs/stringutil"
+	"github.com/AdguardTeam/urlfilter/rules"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Filter data magic bytes used to detect compressed or archived filter list
+// downloads when the URL and HTTP headers don't already give it away.
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	zipMagic  = []byte{0x50, 0x4b, 0x03, 0x04}
 )
 
 var nextFilterID = time.Now().Unix() // semi-stable way to generate an unique ID
@@ -45,6 +57,7 @@ func (f *Filtering) Init() {
 // Start - start the module
 func (f *Filtering) Start() {
 	f.RegisterFilteringHandlers()
+	f.registerFilterRefreshHandler()
 
 	// Here we should start updating filters,
 	//  but currently we can't wake up the periodic task to do so.
@@ -73,15 +86,191 @@ type filter struct {
 	checksum    uint32    // checksum of the file data
 	white       bool
 
+	// ETag is the value of the HTTP ETag response header that was
+	// received the last time this filter was successfully downloaded, if
+	// any.  It is sent back as If-None-Match on the next update so that an
+	// unchanged filter doesn't need to be re-downloaded.
+	ETag string `yaml:"etag,omitempty"`
+
+	// LastModified is the value of the HTTP Last-Modified response header
+	// that was received the last time this filter was successfully
+	// downloaded, if any.  It is sent back as If-Modified-Since on the
+	// next update.
+	LastModified string `yaml:"last_modified,omitempty"`
+
+	// CacheMaxAge is the max-age directive, in seconds, parsed from the
+	// HTTP Cache-Control response header of the last successful download,
+	// if any.  A non-zero value puts a floor under the effective refresh
+	// interval for this filter; see refreshFiltersArray.
+	CacheMaxAge int `yaml:"cache_max_age,omitempty"`
+
+	// UpdateIntervalHours overrides config.DNS.FiltersUpdateIntervalHours
+	// for this filter, if non-zero, so that e.g. a malware list can be
+	// refreshed hourly while an annoyances list is only refreshed weekly.
+	UpdateIntervalHours int `yaml:"update_interval_hours,omitempty"`
+
+	// MaxRetries is the number of attempts updateWithRetry makes to
+	// download this filter before giving up.  Zero means
+	// defaultMaxRetries.
+	MaxRetries int `yaml:"max_retries,omitempty"`
+
+	// BackoffSeconds is how long updateWithRetry waits between retries.
+	// Zero means defaultBackoffSeconds.
+	BackoffSeconds int `yaml:"backoff_seconds,omitempty"`
+
+	// SourceConfig holds backend-specific options for the FilterSource
+	// selected by URL's scheme, e.g. the branch and subdirectory glob for
+	// a "git" source, or the region and credential environment variable
+	// names for an "s3" source.
+	SourceConfig map[string]string `yaml:"source_config,omitempty"`
+
+	// MaxErrorRate is the fraction, from 0 to 1, of rules that may fail to
+	// parse before finalizeUpdate rejects an update and keeps the previous
+	// version of the list.  Zero means defaultMaxFilterErrorRate.
+	MaxErrorRate float64 `yaml:"max_error_rate,omitempty"`
+
+	// Stats is a breakdown, by category, of the rules in this filter, as
+	// of its last successful download.  It isn't computed when the filter
+	// is loaded from disk, so it's empty until the first download.
+	Stats FilterStats `yaml:"-"`
+
 	filtering.Filter `yaml:",inline"`
 }
 
+// defaultMaxFilterErrorRate is used in place of a filter's MaxErrorRate when
+// that field is unset.
+const defaultMaxFilterErrorRate = 0.05
+
+// maxFilterStatsErrors bounds how many parse errors FilterStats.Errors
+// holds, so that a badly broken list can't make it grow without bound.
+const maxFilterStatsErrors = 20
+
+// FilterStats is a breakdown, by rule category, of a filter list's
+// contents, along with the first parse errors encountered, as produced by
+// validateFilterSyntax.
+type FilterStats struct {
+	// NetworkRules is the number of AdBlock-style network (blocking and
+	// exception) rules.
+	NetworkRules int
+
+	// CosmeticRules is the number of AdBlock-style cosmetic rules.
+	CosmeticRules int
+
+	// HostsRules is the number of hosts-file-style rules.
+	HostsRules int
+
+	// ImportantRules is the number of network rules using the $important
+	// modifier.
+	ImportantRules int
+
+	// DNSRewriteRules is the number of network rules using the
+	// $dnsrewrite modifier.
+	DNSRewriteRules int
+
+	// Invalid is the number of lines that didn't match any known rule
+	// syntax.
+	Invalid int
+
+	// Errors holds a human-readable description of up to
+	// maxFilterStatsErrors of the invalid lines.
+	Errors []string
+}
+
+// rejectReason returns a human-readable reason to reject the update these
+// stats describe, given threshold -- the maximum acceptable fraction of
+// invalid rules, or defaultMaxFilterErrorRate if threshold is zero or
+// negative.  It returns "" if the update shouldn't be rejected.
+func (s FilterStats) rejectReason(threshold float64) string {
+	if threshold <= 0 {
+		threshold = defaultMaxFilterErrorRate
+	}
+
+	total := s.NetworkRules + s.CosmeticRules + s.HostsRules + s.Invalid
+	if total == 0 || s.Invalid == 0 {
+		return ""
+	}
+
+	rate := float64(s.Invalid) / float64(total)
+	if rate <= threshold {
+		return ""
+	}
+
+	return fmt.Sprintf(
+		"%d of %d rules (%.1f%%) failed to parse, over the %.1f%% threshold",
+		s.Invalid, total, rate*100, threshold*100,
+	)
+}
+
+// validateFilterSyntax scans file rule by rule, running each one through
+// urlfilter's own rule parser -- the same one the filtering engine uses to
+// apply these lists -- classifying it into one of FilterStats' categories,
+// and collecting up to maxFilterStatsErrors descriptions of the rules that
+// failed to parse.  Unlike parseFilterContents, which only needs a rule
+// count, this is meant to catch a vandalized or truncated upstream list
+// before its update is promoted; see finalizeUpdate.
+func validateFilterSyntax(file io.Reader) (stats FilterStats) {
+	s := bufio.NewScanner(file)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || line[0] == '!' {
+			continue
+		}
+
+		classifyFilterRule(line, &stats)
+	}
+
+	return stats
+}
+
+// classifyFilterRule parses a single non-empty, non-comment-title line from
+// a filter list using urlfilter's rule parser and updates stats according
+// to the resulting rule's type.
+func classifyFilterRule(line string, stats *FilterStats) {
+	rule, err := rules.NewRule(line, 0)
+	if err != nil {
+		stats.Invalid++
+		if len(stats.Errors) < maxFilterStatsErrors {
+			stats.Errors = append(stats.Errors, fmt.Sprintf("%s: %q", err, line))
+		}
+
+		return
+	} else if rule == nil {
+		// A hosts-file-style "#" comment; not counted as a rule.
+		return
+	}
+
+	switch r := rule.(type) {
+	case *rules.NetworkRule:
+		stats.NetworkRules++
+		if r.IsOptionEnabled(rules.OptionImportant) {
+			stats.ImportantRules++
+		}
+		if r.DNSRewrite != nil {
+			stats.DNSRewriteRules++
+		}
+	case *rules.HostRule:
+		stats.HostsRules++
+	case *rules.CosmeticRule:
+		stats.CosmeticRules++
+	default:
+		// A comment, or a rule type we don't have a dedicated bucket for.
+	}
+}
+
+// Default retry policy used by updateWithRetry for filters that don't
+// override MaxRetries/BackoffSeconds.
+const (
+	defaultMaxRetries     = 3
+	defaultBackoffSeconds = 5
+)
+
 const (
 	statusFound          = 1
 	statusEnabledChanged = 2
 	statusURLChanged     = 4
 	statusURLExists      = 8
 	statusUpdateRequired = 0x10
+	statusURLInvalid     = 0x20
 )
 
 // Update properties for a filter specified by its URL
@@ -111,11 +300,25 @@ func (f *Filtering) filterSetProperties(url string, newf filter, whitelist bool)
 			if filterExistsNoLock(newf.URL) {
 				return statusURLExists
 			}
+			if _, err := filterSourceFor(newf.URL); err != nil {
+				log.Error("filter: set properties: %s", err)
+
+				return statusURLInvalid
+			}
 			filt.URL = newf.URL
 			filt.unload()
 			filt.LastUpdated = time.Time{}
 			filt.checksum = 0
 			filt.RulesCount = 0
+
+			// These are validators for filt's *old* URL; sending them
+			// along with a request to the new one could make a server
+			// that happens to reuse the same ETag/Last-Modified answer
+			// 304, and updateIntl would keep serving the old content
+			// forever.
+			filt.ETag = ""
+			filt.LastModified = ""
+			filt.CacheMaxAge = 0
 		}
 
 		if filt.Enabled != newf.Enabled {
@@ -166,8 +369,15 @@ func filterExistsNoLock(url string) bool {
 }
 
 // Add a filter
-// Return FALSE if a filter with this URL exists
+// Return FALSE if a filter with this URL exists or its URL scheme isn't
+// supported by any registered FilterSource
 func filterAdd(f filter) bool {
+	if _, err := filterSourceFor(f.URL); err != nil {
+		log.Error("filter: add: %s", err)
+
+		return false
+	}
+
 	config.Lock()
 	defer config.Unlock()
 
@@ -295,7 +505,20 @@ func (f *Filtering) refreshFiltersArray(filters *[]filter, force bool) (int, []f
 			continue
 		}
 
-		expireTime := f.LastUpdated.Unix() + int64(config.DNS.FiltersUpdateIntervalHours)*60*60
+		updateHours := config.DNS.FiltersUpdateIntervalHours
+		if f.UpdateIntervalHours > 0 {
+			// A per-filter cadence takes precedence over the global one.
+			updateHours = f.UpdateIntervalHours
+		}
+
+		intervalSecs := int64(updateHours) * 60 * 60
+		if cacheSecs := int64(f.CacheMaxAge); cacheSecs > intervalSecs {
+			// Respect the upstream's Cache-Control: max-age so we don't
+			// poll it more often than it wants us to.
+			intervalSecs = cacheSecs
+		}
+
+		expireTime := f.LastUpdated.Unix() + intervalSecs
 		if !force && expireTime > now.Unix() {
 			continue
 		}
@@ -305,6 +528,13 @@ func (f *Filtering) refreshFiltersArray(filters *[]filter, force bool) (int, []f
 		uf.URL = f.URL
 		uf.Name = f.Name
 		uf.checksum = f.checksum
+		uf.ETag = f.ETag
+		uf.LastModified = f.LastModified
+		uf.CacheMaxAge = f.CacheMaxAge
+		uf.MaxRetries = f.MaxRetries
+		uf.BackoffSeconds = f.BackoffSeconds
+		uf.SourceConfig = f.SourceConfig
+		uf.MaxErrorRate = f.MaxErrorRate
 		updateFilters = append(updateFilters, uf)
 	}
 	config.RUnlock()
@@ -316,7 +546,7 @@ func (f *Filtering) refreshFiltersArray(filters *[]filter, force bool) (int, []f
 	nfail := 0
 	for i := range updateFilters {
 		uf := &updateFilters[i]
-		updated, err := f.update(uf)
+		updated, err := f.updateWithRetry(uf)
 		updateFlags = append(updateFlags, updated)
 		if err != nil {
 			nfail++
@@ -342,9 +572,20 @@ func (f *Filtering) refreshFiltersArray(filters *[]filter, force bool) (int, []f
 			}
 			f.LastUpdated = uf.LastUpdated
 			if !updated {
+				// uf.ETag/LastModified/CacheMaxAge may have been stamped
+				// by the fetch even though finalizeUpdate didn't promote
+				// it (e.g. the error-rate threshold rejected it); keep
+				// the validators for the last version that actually made
+				// it to disk, so the next refresh re-fetches instead of
+				// getting a 304 against the rejected content.
 				continue
 			}
 
+			f.ETag = uf.ETag
+			f.LastModified = uf.LastModified
+			f.CacheMaxAge = uf.CacheMaxAge
+			f.Stats = uf.Stats
+
 			log.Info("Updated filter #%d.  Rules: %d -> %d",
 				f.ID, f.RulesCount, uf.RulesCount)
 			f.Name = uf.Name
@@ -427,6 +668,119 @@ func (f *Filtering) refreshFiltersIfNecessary(flags int) (int, bool) {
 	return updateCount, false
 }
 
+// decompressFilterData detects whether body is a zip, gzip, or zstd encoded
+// filter list, checking for those formats in that order, and returns a
+// reader yielding the decompressed, plain-text contents.  Each format is
+// detected by body's magic bytes or flt.URL's suffix; zip also by
+// contentType, and gzip and zstd also by contentEncoding.  So the first
+// format in that order that any of these signals points to wins.  If no known
+// compression is detected, it returns body unchanged, wrapped for peeking.
+func decompressFilterData(
+	body io.Reader,
+	flt *filter,
+	contentEncoding string,
+	contentType string,
+) (r io.Reader, err error) {
+	br := bufio.NewReader(body)
+
+	magic, err := br.Peek(4)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("peeking filter data: %w", err)
+	}
+
+	url := strings.ToLower(flt.URL)
+	contentEncoding = strings.ToLower(contentEncoding)
+	contentType = strings.ToLower(contentType)
+
+	switch {
+	case bytes.HasPrefix(magic, zipMagic),
+		strings.HasSuffix(url, ".zip"),
+		strings.Contains(contentType, "zip"):
+		return unpackZip(br)
+	case bytes.HasPrefix(magic, gzipMagic),
+		strings.HasSuffix(url, ".gz"),
+		strings.Contains(contentEncoding, "gzip"):
+		return gzip.NewReader(br)
+	case bytes.HasPrefix(magic, zstdMagic),
+		strings.HasSuffix(url, ".zst"),
+		strings.Contains(contentEncoding, "zstd"):
+		var zr *zstd.Decoder
+		zr, err = zstd.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("creating zstd reader: %w", err)
+		}
+
+		return zr.IOReadCloser(), nil
+	default:
+		return br, nil
+	}
+}
+
+// unpackZip reads the whole of r as a zip archive and returns the
+// concatenated contents of every file it contains, in archive order, since a
+// filter list mirror may split its rules across several files.
+func unpackZip(r io.Reader) (_ io.Reader, err error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading zip archive: %w", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("opening zip archive: %w", err)
+	}
+
+	buf := &bytes.Buffer{}
+	for _, zf := range zr.File {
+		if zf.FileInfo().IsDir() {
+			continue
+		}
+
+		if err = copyZipFile(buf, zf); err != nil {
+			return nil, err
+		}
+	}
+
+	return buf, nil
+}
+
+// copyZipFile appends the contents of zf to buf.
+func copyZipFile(buf *bytes.Buffer, zf *zip.File) (err error) {
+	rc, err := zf.Open()
+	if err != nil {
+		return fmt.Errorf("opening %s in zip archive: %w", zf.Name, err)
+	}
+	defer func() { err = errors.WithDeferred(err, rc.Close()) }()
+
+	if _, err = io.Copy(buf, rc); err != nil {
+		return fmt.Errorf("reading %s in zip archive: %w", zf.Name, err)
+	}
+
+	return nil
+}
+
+// parseCacheMaxAge extracts the max-age directive, in seconds, from an HTTP
+// Cache-Control header value.  It returns 0 if the header is empty, has no
+// max-age directive, or the directive can't be parsed.
+func parseCacheMaxAge(cacheControl string) int {
+	for _, dir := range strings.Split(cacheControl, ",") {
+		dir = strings.TrimSpace(dir)
+		name, val, found := strings.Cut(dir, "=")
+		if !found || !strings.EqualFold(strings.TrimSpace(name), "max-age") {
+			continue
+		}
+
+		maxAge, err := strconv.Atoi(strings.TrimSpace(val))
+		if err != nil || maxAge < 0 {
+			return 0
+		}
+
+		return maxAge
+	}
+
+	return 0
+}
+
 // Allows printable UTF-8 text with CR, LF, TAB characters
 func isPrintableText(data []byte, len int) bool {
 	for i := 0; i < len; i++ {
@@ -488,6 +842,116 @@ func (f *Filtering) update(filter *filter) (bool, error) {
 	return b, err
 }
 
+// updateWithRetry behaves like update, but retries a failed update, making up
+// to flt.MaxRetries attempts in all (defaultMaxRetries if unset), waiting
+// flt.BackoffSeconds (defaultBackoffSeconds if unset) between attempts.
+func (f *Filtering) updateWithRetry(flt *filter) (updated bool, err error) {
+	maxRetries := flt.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = defaultMaxRetries
+	}
+
+	backoff := flt.BackoffSeconds
+	if backoff <= 0 {
+		backoff = defaultBackoffSeconds
+	}
+
+	for attempt := 1; ; attempt++ {
+		updated, err = f.update(flt)
+		if err == nil || attempt >= maxRetries {
+			return updated, err
+		}
+
+		log.Debug("filter: retrying update of %s after error (attempt %d/%d): %s",
+			flt.URL, attempt, maxRetries, err)
+		time.Sleep(time.Duration(backoff) * time.Second)
+	}
+}
+
+// refreshFilterByID refreshes the single filter with the given id, ignoring
+// f.refreshStatus/f.refreshLock so that a global refresh already in progress
+// doesn't make a user wait for it before a targeted, single-filter refresh
+// can run.
+func (f *Filtering) refreshFilterByID(id int64, whitelist bool) (updated bool, err error) {
+	filters := &config.Filters
+	if whitelist {
+		filters = &config.WhitelistFilters
+	}
+
+	config.RLock()
+	var uf filter
+	found := false
+	for i := range *filters {
+		flt := &(*filters)[i]
+		if flt.ID != id {
+			continue
+		}
+
+		found = true
+		if !flt.Enabled {
+			break
+		}
+
+		uf.ID = flt.ID
+		uf.URL = flt.URL
+		uf.Name = flt.Name
+		uf.checksum = flt.checksum
+		uf.ETag = flt.ETag
+		uf.LastModified = flt.LastModified
+		uf.CacheMaxAge = flt.CacheMaxAge
+		uf.MaxRetries = flt.MaxRetries
+		uf.BackoffSeconds = flt.BackoffSeconds
+		uf.SourceConfig = flt.SourceConfig
+		uf.MaxErrorRate = flt.MaxErrorRate
+
+		break
+	}
+	config.RUnlock()
+
+	if !found {
+		return false, fmt.Errorf("filter %d: %w", id, os.ErrNotExist)
+	} else if uf.ID == 0 {
+		return false, fmt.Errorf("filter %d is disabled", id)
+	}
+
+	updated, err = f.updateWithRetry(&uf)
+	if err != nil {
+		return false, fmt.Errorf("refreshing filter %d: %w", id, err)
+	}
+
+	config.Lock()
+	for i := range *filters {
+		flt := &(*filters)[i]
+		if flt.ID != id {
+			continue
+		}
+
+		flt.LastUpdated = uf.LastUpdated
+		if updated {
+			// Only keep the new cache validators if the update was
+			// actually promoted; otherwise they may describe content
+			// finalizeUpdate rejected, and persisting them would make
+			// the next refresh 304 against it instead of re-fetching.
+			flt.ETag = uf.ETag
+			flt.LastModified = uf.LastModified
+			flt.CacheMaxAge = uf.CacheMaxAge
+			flt.Name = uf.Name
+			flt.RulesCount = uf.RulesCount
+			flt.checksum = uf.checksum
+			flt.Stats = uf.Stats
+		}
+
+		break
+	}
+	config.Unlock()
+
+	if updated {
+		enableFilters(false)
+	}
+
+	return updated, nil
+}
+
 func (f *Filtering) read(reader io.Reader, tmpFile *os.File, filter *filter) (int, error) {
 	htmlTest := true
 	firstChunk := make([]byte, 4*1024)
@@ -536,9 +1000,12 @@ func (f *Filtering) read(reader io.Reader, tmpFile *os.File, filter *filter) (in
 	}
 }
 
-// finalizeUpdate closes and gets rid of temporary file f with filter's content
-// according to updated.  It also saves new values of flt's name, rules number
-// and checksum if sucсeeded.
+// finalizeUpdate closes and gets rid of temporary file f with filter's
+// content according to updated.  If updated, and stats' error rate doesn't
+// exceed flt's threshold, it promotes f to flt.Path() and saves new values
+// of flt's name, rules number, checksum, and stats; otherwise, the previous
+// version of the filter, if any, is left untouched.  promoted reports
+// whether f's content was actually promoted.
 func finalizeUpdate(
 	f *os.File,
 	flt *filter,
@@ -546,54 +1013,68 @@ func finalizeUpdate(
 	name string,
 	rnum int,
 	cs uint32,
-) (err error) {
+	stats FilterStats,
+) (promoted bool, err error) {
 	tmpFileName := f.Name()
 
 	// Close the file before renaming it because it's required on Windows.
 	//
 	// See https://github.com/adguardTeam/adGuardHome/issues/1553.
 	if err = f.Close(); err != nil {
-		return fmt.Errorf("closing temporary file: %w", err)
+		return false, fmt.Errorf("closing temporary file: %w", err)
 	}
 
 	if !updated {
 		log.Tracef("filter #%d from %s has no changes, skip", flt.ID, flt.URL)
 
-		return os.Remove(tmpFileName)
+		return false, os.Remove(tmpFileName)
+	}
+
+	if reason := stats.rejectReason(flt.MaxErrorRate); reason != "" {
+		log.Error("filter #%d from %s: %s; keeping the previous version", flt.ID, flt.URL, reason)
+
+		return false, os.Remove(tmpFileName)
 	}
 
 	log.Printf("saving filter %d contents to: %s", flt.ID, flt.Path())
 
 	if err = os.Rename(tmpFileName, flt.Path()); err != nil {
-		return errors.WithDeferred(err, os.Remove(tmpFileName))
+		return false, errors.WithDeferred(err, os.Remove(tmpFileName))
 	}
 
 	flt.Name = stringutil.Coalesce(flt.Name, name)
 	flt.checksum = cs
 	flt.RulesCount = rnum
+	flt.Stats = stats
 
-	return nil
+	return true, nil
 }
 
-// processUpdate copies filter's content from src to dst and returns the name,
-// rules number, and checksum for it.  It also returns the number of bytes read
-// from src.
+// processUpdate copies filter's content from src to dst and returns the
+// name, rules number, checksum, and per-category rule stats for it.  It
+// also returns the number of bytes read from src.
 func (f *Filtering) processUpdate(
 	src io.Reader,
 	dst *os.File,
 	flt *filter,
-) (name string, rnum int, cs uint32, n int, err error) {
+) (name string, rnum int, cs uint32, stats FilterStats, n int, err error) {
 	if n, err = f.read(src, dst, flt); err != nil {
-		return "", 0, 0, 0, err
+		return "", 0, 0, FilterStats{}, 0, err
 	}
 
 	if _, err = dst.Seek(0, io.SeekStart); err != nil {
-		return "", 0, 0, 0, err
+		return "", 0, 0, FilterStats{}, 0, err
 	}
 
 	rnum, cs, name = f.parseFilterContents(dst)
 
-	return name, rnum, cs, n, nil
+	if _, err = dst.Seek(0, io.SeekStart); err != nil {
+		return "", 0, 0, FilterStats{}, 0, err
+	}
+
+	stats = validateFilterSyntax(dst)
+
+	return name, rnum, cs, stats, n, nil
 }
 
 // updateIntl updates the flt rewriting it's actual file.  It returns true if
@@ -604,6 +1085,7 @@ func (f *Filtering) updateIntl(flt *filter) (ok bool, err error) {
 	var name string
 	var rnum, n int
 	var cs uint32
+	var stats FilterStats
 
 	var tmpFile *os.File
 	tmpFile, err = os.CreateTemp(filepath.Join(Context.getDataDir(), filterDir), "")
@@ -611,8 +1093,9 @@ func (f *Filtering) updateIntl(flt *filter) (ok bool, err error) {
 		return false, err
 	}
 	defer func() {
-		err = errors.WithDeferred(err, finalizeUpdate(tmpFile, flt, ok, name, rnum, cs))
-		ok = ok && err == nil
+		promoted, finErr := finalizeUpdate(tmpFile, flt, ok, name, rnum, cs, stats)
+		err = errors.WithDeferred(err, finErr)
+		ok = promoted && err == nil
 		if ok {
 			log.Printf("updated filter %d: %d bytes, %d rules", flt.ID, n, rnum)
 		}
@@ -626,36 +1109,35 @@ func (f *Filtering) updateIntl(flt *filter) (ok bool, err error) {
 		return false, fmt.Errorf("changing file mode: %w", err)
 	}
 
-	var r io.Reader
-	if filepath.IsAbs(flt.URL) {
-		var file io.ReadCloser
-		file, err = os.Open(flt.URL)
-		if err != nil {
-			return false, fmt.Errorf("open file: %w", err)
-		}
-		defer func() { err = errors.WithDeferred(err, file.Close()) }()
+	src, err := filterSourceFor(flt.URL)
+	if err != nil {
+		return false, fmt.Errorf("selecting filter source: %w", err)
+	}
 
-		r = file
-	} else {
-		var resp *http.Response
-		resp, err = Context.client.Get(flt.URL)
-		if err != nil {
-			log.Printf("requesting filter from %s, skip: %s", flt.URL, err)
+	body, notModified, contentEncoding, contentType, err := src.Fetch(flt)
+	if err != nil {
+		log.Printf("fetching filter from %s, skip: %s", flt.URL, err)
 
-			return false, err
-		}
-		defer func() { err = errors.WithDeferred(err, resp.Body.Close()) }()
+		return false, err
+	}
 
-		if resp.StatusCode != http.StatusOK {
-			log.Printf("got status code %d from %s, skip", resp.StatusCode, flt.URL)
+	if notModified {
+		log.Tracef("filter %d from %s hasn't changed, skip", flt.ID, flt.URL)
 
-			return false, fmt.Errorf("got status code != 200: %d", resp.StatusCode)
-		}
+		return false, nil
+	}
+	defer func() { err = errors.WithDeferred(err, body.Close()) }()
 
-		r = resp.Body
+	var r io.Reader
+	r, err = decompressFilterData(body, flt, contentEncoding, contentType)
+	if err != nil {
+		return false, fmt.Errorf("decompressing filter data from %s: %w", flt.URL, err)
+	}
+	if rc, ok := r.(io.Closer); ok {
+		defer func() { err = errors.WithDeferred(err, rc.Close()) }()
 	}
 
-	name, rnum, cs, n, err = f.processUpdate(r, tmpFile, flt)
+	name, rnum, cs, stats, n, err = f.processUpdate(r, tmpFile, flt)
 
 	return cs != flt.checksum, err
 }
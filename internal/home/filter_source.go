@@ -0,0 +1,144 @@
+package home
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/AdguardTeam/golibs/log"
+)
+
+// FilterSource downloads the raw contents of a filter list from some
+// backend, chosen by a filter's URL scheme.  Implementations are registered
+// in filterSources and looked up by filterSourceFor.
+type FilterSource interface {
+	// Fetch retrieves flt's contents and returns them as body, which the
+	// caller must close.  If notModified is true, body is nil and the
+	// caller should treat the filter as unchanged, the same way it would
+	// an HTTP 304 Not Modified.  Fetch may update flt's ETag, LastModified,
+	// and CacheMaxAge fields as a side effect, for sources that support
+	// conditional requests.
+	Fetch(flt *filter) (body io.ReadCloser, notModified bool, contentEncoding, contentType string, err error)
+}
+
+// filterSources maps a URL scheme to the FilterSource that handles it.
+var filterSources = map[string]FilterSource{
+	"file":  fileFilterSource{},
+	"http":  httpFilterSource{},
+	"https": httpFilterSource{},
+	"git":   gitFilterSource{},
+	"s3":    s3FilterSource{},
+}
+
+// filterSourceScheme returns the scheme that determines which FilterSource
+// handles rawURL.  A bare absolute path, with no scheme of its own, is
+// treated as "file" for backwards compatibility with configurations that
+// predate pluggable filter sources.
+func filterSourceScheme(rawURL string) (scheme string, err error) {
+	if filepath.IsAbs(rawURL) {
+		return "file", nil
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("parsing filter URL: %w", err)
+	}
+
+	if u.Scheme == "" {
+		return "", fmt.Errorf("filter URL %q has no scheme", rawURL)
+	}
+
+	if strings.HasPrefix(u.Scheme, "git+") {
+		// e.g. "git+https://" or "git+ssh://" -- a repository hosted over
+		// another protocol that should still use gitFilterSource.
+		return "git", nil
+	}
+
+	return u.Scheme, nil
+}
+
+// filterSourceFor returns the FilterSource registered for rawURL's scheme.
+func filterSourceFor(rawURL string) (FilterSource, error) {
+	scheme, err := filterSourceScheme(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	src, ok := filterSources[scheme]
+	if !ok {
+		return nil, fmt.Errorf("unsupported filter source scheme %q", scheme)
+	}
+
+	return src, nil
+}
+
+// fileFilterSource reads a filter list from the local filesystem, either a
+// bare absolute path or a "file://" URL.
+type fileFilterSource struct{}
+
+// Fetch implements the FilterSource interface for fileFilterSource.
+func (fileFilterSource) Fetch(
+	flt *filter,
+) (body io.ReadCloser, notModified bool, contentEncoding, contentType string, err error) {
+	path := flt.URL
+	if u, uErr := url.Parse(flt.URL); uErr == nil && u.Scheme == "file" {
+		path = u.Path
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, false, "", "", fmt.Errorf("open file: %w", err)
+	}
+
+	return f, false, "", "", nil
+}
+
+// httpFilterSource downloads a filter list over HTTP(S), using the
+// conditional-request metadata stored on flt to avoid re-downloading an
+// unchanged list.
+type httpFilterSource struct{}
+
+// Fetch implements the FilterSource interface for httpFilterSource.
+func (httpFilterSource) Fetch(
+	flt *filter,
+) (body io.ReadCloser, notModified bool, contentEncoding, contentType string, err error) {
+	req, err := http.NewRequest(http.MethodGet, flt.URL, nil)
+	if err != nil {
+		return nil, false, "", "", fmt.Errorf("creating request: %w", err)
+	}
+
+	if flt.ETag != "" {
+		req.Header.Set("If-None-Match", flt.ETag)
+	}
+	if flt.LastModified != "" {
+		req.Header.Set("If-Modified-Since", flt.LastModified)
+	}
+
+	resp, err := Context.client.Do(req)
+	if err != nil {
+		return nil, false, "", "", err
+	}
+
+	if resp.StatusCode == http.StatusNotModified {
+		log.Debug("filter source: %s: not modified", flt.URL)
+		_ = resp.Body.Close()
+
+		return nil, true, "", "", nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		_ = resp.Body.Close()
+
+		return nil, false, "", "", fmt.Errorf("got status code != 200: %d", resp.StatusCode)
+	}
+
+	flt.ETag = resp.Header.Get("ETag")
+	flt.LastModified = resp.Header.Get("Last-Modified")
+	flt.CacheMaxAge = parseCacheMaxAge(resp.Header.Get("Cache-Control"))
+
+	return resp.Body, false, resp.Header.Get("Content-Encoding"), resp.Header.Get("Content-Type"), nil
+}
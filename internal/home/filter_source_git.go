@@ -0,0 +1,157 @@
+package home
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/AdguardTeam/golibs/errors"
+	"github.com/AdguardTeam/golibs/log"
+)
+
+// gitFilterSource assembles a filter list by cloning (or pulling) a git
+// repository and concatenating the files inside it that match a glob.  The
+// "branch" and "glob" keys of flt.SourceConfig select, respectively, the
+// branch to check out and which files within the repository make up the
+// list; glob defaults to "*" and is relative to the repository root.
+type gitFilterSource struct{}
+
+// Fetch implements the FilterSource interface for gitFilterSource.
+func (gitFilterSource) Fetch(
+	flt *filter,
+) (body io.ReadCloser, notModified bool, contentEncoding, contentType string, err error) {
+	repoDir := filepath.Join(Context.getDataDir(), filterDir, "git-cache", strconv.FormatInt(flt.ID, 10))
+
+	// "git://host/repo.git" is passed to git as-is, since it's a protocol
+	// git understands natively.  "git+https://host/repo.git" and similar
+	// let a repo hosted over another protocol opt into the git source.
+	repoURL := strings.TrimPrefix(flt.URL, "git+")
+	if strings.Contains(repoURL, "::") {
+		// Reject the "ext::<command>"/"fd::<fd>" remote helper syntax up
+		// front: git's transport layer would otherwise run <command>
+		// itself, regardless of our scheme whitelist.
+		return nil, false, "", "", fmt.Errorf("git filter source: remote helper URLs are not allowed: %q", repoURL)
+	}
+
+	branch := flt.SourceConfig["branch"]
+	if err = validateGitRefName(branch); err != nil {
+		return nil, false, "", "", fmt.Errorf("git filter source: branch: %w", err)
+	}
+
+	if err = gitCloneOrPull(repoURL, repoDir, branch); err != nil {
+		return nil, false, "", "", err
+	}
+
+	glob := flt.SourceConfig["glob"]
+	if glob == "" {
+		glob = "*"
+	} else if err = validateGitRefName(glob); err != nil {
+		return nil, false, "", "", fmt.Errorf("git filter source: glob: %w", err)
+	}
+
+	matches, err := filepath.Glob(filepath.Join(repoDir, glob))
+	if err != nil {
+		return nil, false, "", "", fmt.Errorf("matching glob %q: %w", glob, err)
+	}
+
+	sort.Strings(matches)
+
+	buf := &bytes.Buffer{}
+	for _, m := range matches {
+		if err = appendFileContents(buf, m); err != nil {
+			return nil, false, "", "", err
+		}
+	}
+
+	return io.NopCloser(buf), false, "", "", nil
+}
+
+// validateGitRefName returns an error if name isn't safe to pass as a
+// positional argument to git: empty is always fine (the caller treats it as
+// "unset"), but a leading "-" would let git parse it as a flag instead of a
+// ref name or pathspec -- e.g. "--upload-pack=<command>" makes git exec
+// <command> locally.
+func validateGitRefName(name string) error {
+	if name != "" && strings.HasPrefix(name, "-") {
+		return fmt.Errorf("must not start with %q: %q", "-", name)
+	}
+
+	return nil
+}
+
+// gitCloneOrPull clones repoURL into dir at branch, if dir doesn't contain a
+// git repository yet.  Otherwise, if branch is unset, it fast-forward pulls
+// the branch that is checked out; if branch is set, it fetches branch and
+// resets a local branch of that name to the fetched commit, so that editing
+// source_config.branch on an already-cloned filter takes effect on the next
+// refresh.
+func gitCloneOrPull(repoURL, dir, branch string) (err error) {
+	if _, err = os.Stat(filepath.Join(dir, ".git")); err == nil {
+		if branch == "" {
+			return runGit(dir, "pull", "--ff-only")
+		}
+
+		// Fetch the configured branch explicitly and reset to it: a plain
+		// "pull" only tracks whatever branch the initial clone checked
+		// out, so it wouldn't notice source_config.branch changing later,
+		// and the branch may not even be present in a shallow clone made
+		// for a different one.
+		if err = runGit(dir, "fetch", "--depth", "1", "origin", branch); err != nil {
+			return err
+		}
+
+		return runGit(dir, "checkout", "-B", branch, "FETCH_HEAD")
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("checking git cache dir: %w", err)
+	}
+
+	if err = os.MkdirAll(filepath.Dir(dir), 0o755); err != nil {
+		return fmt.Errorf("creating git cache dir: %w", err)
+	}
+
+	args := []string{"clone", "--depth", "1"}
+	if branch != "" {
+		args = append(args, "--branch", branch)
+	}
+	args = append(args, repoURL, dir)
+
+	return runGit("", args...)
+}
+
+// runGit runs the git command-line tool with args in dir, which may be
+// empty for commands that take their target directory as an argument.
+func runGit(dir string, args ...string) error {
+	log.Debug("filter source: git: running %q in %q", args, dir)
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	// Belt-and-braces alongside the "::" check in Fetch: even if a
+	// remote-helper URL slipped through, git itself refuses to use any
+	// transport outside this allowlist.
+	cmd.Env = append(os.Environ(), "GIT_ALLOW_PROTOCOL=file:git:http:https:ssh")
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, out)
+	}
+
+	return nil
+}
+
+// appendFileContents appends the contents of the file at path to buf.
+func appendFileContents(buf *bytes.Buffer, path string) (err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	_, err = buf.Write(data)
+
+	return err
+}
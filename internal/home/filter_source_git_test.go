@@ -0,0 +1,46 @@
+package home
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateGitRefName(t *testing.T) {
+	testCases := []struct {
+		name    string
+		ref     string
+		wantErr bool
+	}{{
+		name:    "empty",
+		ref:     "",
+		wantErr: false,
+	}, {
+		name:    "plain_branch",
+		ref:     "main",
+		wantErr: false,
+	}, {
+		name:    "glob",
+		ref:     "*.txt",
+		wantErr: false,
+	}, {
+		name:    "flag_injection",
+		ref:     "--upload-pack=touch /tmp/PWNED",
+		wantErr: true,
+	}, {
+		name:    "bare_dash",
+		ref:     "-",
+		wantErr: true,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateGitRefName(tc.ref)
+			if tc.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
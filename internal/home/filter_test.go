@@ -0,0 +1,160 @@
+package home
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifyFilterRule(t *testing.T) {
+	testCases := []struct {
+		name      string
+		line      string
+		wantStats FilterStats
+	}{{
+		name:      "network",
+		line:      "||example.com^",
+		wantStats: FilterStats{NetworkRules: 1},
+	}, {
+		name:      "network_important",
+		line:      "||example.com^$important",
+		wantStats: FilterStats{NetworkRules: 1, ImportantRules: 1},
+	}, {
+		name:      "network_dnsrewrite",
+		line:      "||example.com^$dnsrewrite=1.2.3.4",
+		wantStats: FilterStats{NetworkRules: 1, DNSRewriteRules: 1},
+	}, {
+		name:      "hosts",
+		line:      "0.0.0.0 example.com",
+		wantStats: FilterStats{HostsRules: 1},
+	}, {
+		name:      "cosmetic",
+		line:      "example.com##.ad-banner",
+		wantStats: FilterStats{CosmeticRules: 1},
+	}, {
+		name:      "comment",
+		line:      "# a comment",
+		wantStats: FilterStats{},
+	}, {
+		name:      "invalid",
+		line:      "$$$not,a,valid::rule$$$",
+		wantStats: FilterStats{Invalid: 1},
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			stats := FilterStats{}
+			classifyFilterRule(tc.line, &stats)
+
+			tc.wantStats.Errors = stats.Errors
+			assert.Equal(t, tc.wantStats, stats)
+			if tc.wantStats.Invalid > 0 {
+				require.Len(t, stats.Errors, 1)
+				assert.Contains(t, stats.Errors[0], tc.line)
+			}
+		})
+	}
+}
+
+func TestValidateFilterSyntax(t *testing.T) {
+	list := strings.Join([]string{
+		"! a title comment",
+		"||good-one.example^",
+		"0.0.0.0 good-two.example",
+		"garbled\x00line\x01with\x02no\x03meaning",
+	}, "\n")
+
+	stats := validateFilterSyntax(strings.NewReader(list))
+	assert.Equal(t, 1, stats.NetworkRules)
+	assert.Equal(t, 1, stats.HostsRules)
+	assert.Equal(t, 1, stats.Invalid)
+}
+
+func TestFilterStats_rejectReason(t *testing.T) {
+	testCases := []struct {
+		name      string
+		stats     FilterStats
+		threshold float64
+		wantEmpty bool
+	}{{
+		name:      "no_rules",
+		stats:     FilterStats{},
+		threshold: 0.05,
+		wantEmpty: true,
+	}, {
+		name:      "no_invalid",
+		stats:     FilterStats{NetworkRules: 100},
+		threshold: 0.05,
+		wantEmpty: true,
+	}, {
+		name:      "under_threshold",
+		stats:     FilterStats{NetworkRules: 99, Invalid: 1},
+		threshold: 0.05,
+		wantEmpty: true,
+	}, {
+		name:      "over_threshold",
+		stats:     FilterStats{NetworkRules: 90, Invalid: 10},
+		threshold: 0.05,
+		wantEmpty: false,
+	}, {
+		name:      "zero_threshold_uses_default",
+		stats:     FilterStats{NetworkRules: 90, Invalid: 10},
+		threshold: 0,
+		wantEmpty: false,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			reason := tc.stats.rejectReason(tc.threshold)
+			if tc.wantEmpty {
+				assert.Empty(t, reason)
+			} else {
+				assert.NotEmpty(t, reason)
+			}
+		})
+	}
+}
+
+func TestParseCacheMaxAge(t *testing.T) {
+	testCases := []struct {
+		name         string
+		cacheControl string
+		want         int
+	}{{
+		name:         "empty",
+		cacheControl: "",
+		want:         0,
+	}, {
+		name:         "simple",
+		cacheControl: "max-age=3600",
+		want:         3600,
+	}, {
+		name:         "with_other_directives",
+		cacheControl: "public, max-age=600, must-revalidate",
+		want:         600,
+	}, {
+		name:         "case_insensitive_and_spaced",
+		cacheControl: "MAX-AGE = 120",
+		want:         120,
+	}, {
+		name:         "no_max_age",
+		cacheControl: "no-cache",
+		want:         0,
+	}, {
+		name:         "negative",
+		cacheControl: "max-age=-1",
+		want:         0,
+	}, {
+		name:         "garbage",
+		cacheControl: "max-age=not-a-number",
+		want:         0,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, parseCacheMaxAge(tc.cacheControl))
+		})
+	}
+}